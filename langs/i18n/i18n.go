@@ -0,0 +1,536 @@
+// Copyright 2019 The Hugo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package i18n deals with translation strings etc.
+package i18n
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gohugoio/hugo/common/loggers"
+	"github.com/gohugoio/hugo/deps"
+
+	toml "github.com/BurntSushi/toml"
+	"github.com/gotnospirit/messageformat"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/projectfluent/fluent.go/fluent"
+	"github.com/spf13/afero"
+	"golang.org/x/text/language"
+)
+
+// translateFunc is the function signature handed back to the templates, e.g.
+// {{ T "hello" }}.
+type translateFunc func(translationID string, templateData interface{}) string
+
+// translateLookupFunc resolves a translation id against a single
+// language's bundles, reporting ok=false - rather than invoking
+// cfg.missing itself - when none of the loaded message syntaxes had it.
+// Keeping the miss unreported here lets Func invoke cfg.missing with
+// whatever language was actually requested, even when the lookup it's
+// running belongs to the default content language's fallback path.
+type translateLookupFunc func(translationID string, args interface{}) (string, bool)
+
+// Translator handles translation lookups for all configured languages. It
+// can be backed by a mix of go-i18n TOML bundles, ICU MessageFormat
+// messages embedded in those same TOML files, and Project Fluent FTL
+// bundles, one set per language.
+type Translator struct {
+	translateFuncs map[string]translateFunc
+	lookupFuncs    map[string]translateLookupFunc
+	cfg            translatorConfig
+	logger         loggers.Logger
+}
+
+// MissingTranslationFunc is invoked whenever a translation for a given
+// language/id cannot be resolved from any of the loaded message syntaxes.
+// It returns the replacement string to render in its place. Implementations
+// can wire in machine-translation fallbacks, emit metrics, or write the
+// missing id out to a report for translator handoff. It is not invoked
+// when neither the requested nor the default content language has any
+// translations loaded.
+type MissingTranslationFunc func(lang, id string, args interface{}) (string, bool)
+
+// defaultMissingTranslationFunc reproduces Hugo's historical behavior: an
+// empty string normally, or a "[i18n] id" placeholder when
+// enableMissingTranslationPlaceholders is set, to help translators spot
+// what's left to translate.
+func defaultMissingTranslationFunc(enablePlaceholders bool) MissingTranslationFunc {
+	return func(lang, id string, args interface{}) (string, bool) {
+		if !enablePlaceholders {
+			return "", true
+		}
+		return fmt.Sprintf("[i18n] %s", id), true
+	}
+}
+
+// translatorConfig is the subset of TranslationProvider state that the
+// Translator needs once it has been built.
+type translatorConfig struct {
+	defaultContentLanguage string
+	enablePlaceholders     bool
+	missing                MissingTranslationFunc
+}
+
+// NewTranslator creates a new Translator for the given filesystem and
+// configuration.
+func NewTranslator(fs afero.Fs, cfg translatorConfig, logger loggers.Logger) (Translator, error) {
+	if cfg.missing == nil {
+		cfg.missing = defaultMissingTranslationFunc(cfg.enablePlaceholders)
+	}
+
+	t := Translator{
+		cfg:            cfg,
+		logger:         logger,
+		translateFuncs: make(map[string]translateFunc),
+		lookupFuncs:    make(map[string]translateLookupFunc),
+	}
+	if err := t.loadTranslations(fs); err != nil {
+		return t, fmt.Errorf("failed to load translations: %w", err)
+	}
+	return t, nil
+}
+
+// Func returns the translate func for the given language, falling back to
+// the default content language, and finally, when the default content
+// language has no translations loaded either, to a func that always
+// returns "" without consulting cfg.missing. Otherwise the requested lang
+// is the one reported to cfg.missing on a miss, even when the lookup falls
+// back to the default content language's bundles.
+func (t Translator) Func(lang string) translateFunc {
+	if f, ok := t.translateFuncs[lang]; ok {
+		return f
+	}
+	t.logger.Infof("Translation func for language %v not found, use default.", lang)
+	if lookup, ok := t.lookupFuncs[t.cfg.defaultContentLanguage]; ok {
+		return t.wrapWithMissing(lang, lookup)
+	}
+	t.logger.Infoln("i18n not initialized; used empty translate func.")
+	return func(translationID string, args interface{}) string {
+		return ""
+	}
+}
+
+// loadTranslations walks the i18n filesystem and builds one translateFunc
+// per language found, merging go-i18n TOML/JSON message files and
+// Project Fluent FTL files into a single lookup per language.
+func (t *Translator) loadTranslations(fs afero.Fs) error {
+	t.translateFuncs = make(map[string]translateFunc)
+	t.lookupFuncs = make(map[string]translateLookupFunc)
+
+	bundle := i18n.NewBundle(language.English)
+	bundle.RegisterUnmarshalFunc("toml", toml.Unmarshal)
+
+	fluentBundles := make(map[string]*fluent.Bundle)
+	icuBundles := make(map[string]*icuBundle)
+
+	files, err := afero.ReadDir(fs, "")
+	if err != nil {
+		return nil
+	}
+
+	for _, fi := range files {
+		if fi.IsDir() {
+			continue
+		}
+		name := fi.Name()
+		ext := filepath.Ext(name)
+		// Keys are lower case (e.g. "pt-br"), the same as Hugo's language keys.
+		lang := strings.ToLower(strings.TrimSuffix(name, ext))
+
+		data, err := afero.ReadFile(fs, name)
+		if err != nil {
+			return fmt.Errorf("failed to read translation file %q: %w", name, err)
+		}
+
+		// An empty file (or one with no messages) is still a valid TOML or
+		// FTL document, and the language it names must still be
+		// registered, or lookups for that language fall all the way
+		// through to Translator.Func's always-"" no-op default and never
+		// reach the default-language fallback or cfg.missing at all.
+		switch ext {
+		case ".ftl":
+			fb, err := newFluentBundle(lang, data)
+			if err != nil {
+				return fmt.Errorf("failed to parse Fluent translations for %q: %w", lang, err)
+			}
+			fluentBundles[lang] = fb
+		default:
+			if ib := newICUBundle(t.logger, lang, data); ib != nil {
+				icuBundles[lang] = ib
+			}
+			if _, err := bundle.ParseMessageFileBytes(data, name); err != nil {
+				return fmt.Errorf("failed to load translations for %q: %w", lang, err)
+			}
+		}
+	}
+
+	languages := make(map[string]bool)
+	for _, t := range bundle.LanguageTags() {
+		languages[strings.ToLower(t.String())] = true
+	}
+	for lang := range fluentBundles {
+		languages[lang] = true
+	}
+	for lang := range icuBundles {
+		languages[lang] = true
+	}
+
+	defaultLang := t.cfg.defaultContentLanguage
+
+	for lang := range languages {
+		// Placeholder mode is meant to flag anything not translated in the
+		// current language, so it intentionally skips the default-language
+		// fallback that normal rendering relies on. go-i18n itself falls
+		// back to the bundle's default language, so in this mode only a
+		// go-i18n result in lang's own tag is accepted.
+		localizerLangs := []string{lang}
+		fbDefault := fluentBundles[defaultLang]
+		ibDefault := icuBundles[defaultLang]
+		onlyTag := ""
+		if !t.cfg.enablePlaceholders {
+			localizerLangs = append(localizerLangs, defaultLang)
+		} else {
+			fbDefault = nil
+			ibDefault = nil
+			onlyTag = language.Make(lang).String()
+		}
+
+		localizer := i18n.NewLocalizer(bundle, localizerLangs...)
+		fb := fluentBundles[lang]
+		ib := icuBundles[lang]
+		lookup := newLookupFunc(localizer, onlyTag, ib, ibDefault, fb, fbDefault)
+		t.lookupFuncs[lang] = lookup
+		t.translateFuncs[lang] = t.wrapWithMissing(lang, lookup)
+	}
+
+	return nil
+}
+
+// newLookupFunc builds the translateLookupFunc for a single language,
+// trying the go-i18n localizer first, then any ICU MessageFormat
+// messages, then the Fluent bundles (if any). When onlyTag is set, a
+// go-i18n result found under any other language tag counts as not found.
+func newLookupFunc(localizer *i18n.Localizer, onlyTag string, ib, ibDefault *icuBundle, fb, fbDefault *fluent.Bundle) translateLookupFunc {
+	return func(translationID string, args interface{}) (string, bool) {
+		pluralCount := getPluralCount(args)
+
+		translated, tag, err := localizer.LocalizeWithTag(&i18n.LocalizeConfig{
+			MessageID:    translationID,
+			TemplateData: args,
+			PluralCount:  pluralCount,
+		})
+		if err == nil && (onlyTag == "" || tag.String() == onlyTag) {
+			return translated, true
+		}
+
+		vars := toMessageArgs(args)
+
+		if s, ok := ib.Format(translationID, vars); ok {
+			return s, true
+		}
+		if ibDefault != ib {
+			if s, ok := ibDefault.Format(translationID, vars); ok {
+				return s, true
+			}
+		}
+
+		if fb != nil {
+			if s, ok := fb.Format(translationID, vars); ok {
+				return s, true
+			}
+		}
+		if fbDefault != nil && fbDefault != fb {
+			if s, ok := fbDefault.Format(translationID, vars); ok {
+				return s, true
+			}
+		}
+
+		return "", false
+	}
+}
+
+// wrapWithMissing turns a lookup into the public translateFunc, reporting
+// lang - the language Func was actually asked to translate for - to
+// cfg.missing on every miss. This matters when lookup is the default
+// content language's fallback func: without threading lang through
+// explicitly, cfg.missing would see the default language instead of the
+// one that was actually requested.
+func (t Translator) wrapWithMissing(lang string, lookup translateLookupFunc) translateFunc {
+	return func(translationID string, args interface{}) string {
+		if s, ok := lookup(translationID, args); ok {
+			return s
+		}
+		if s, ok := t.cfg.missing(lang, translationID, args); ok {
+			return s
+		}
+		return ""
+	}
+}
+
+// newFluentBundle parses a single language's FTL source into a Fluent
+// bundle. Fluent's selectors (gender, case, plural) and term references
+// give authors more expressive power than go-i18n's one/other forms.
+func newFluentBundle(lang string, data []byte) (*fluent.Bundle, error) {
+	fb := fluent.NewBundle(lang)
+	if err := fb.ParseMessages(string(data)); err != nil {
+		return nil, err
+	}
+	return fb, nil
+}
+
+// icuBundle holds the ICU MessageFormat messages compiled for a single
+// language, keyed by message ID with the ".icu" marker suffix stripped.
+type icuBundle struct {
+	messages map[string]*messageformat.Formatter
+}
+
+// Format renders the named ICU message with the given variables. It is
+// nil-safe so callers don't need to special-case languages with no ICU
+// messages.
+func (b *icuBundle) Format(id string, vars map[string]interface{}) (string, bool) {
+	if b == nil {
+		return "", false
+	}
+	fm, ok := b.messages[id]
+	if !ok {
+		return "", false
+	}
+	s, err := fm.FormatMap(vars)
+	if err != nil {
+		return "", false
+	}
+	return s, true
+}
+
+// newICUBundle scans a TOML translation file for keys marked with an
+// ".icu" suffix (e.g. "hello.icu = \"...\"") and compiles their values as
+// ICU MessageFormat patterns, letting a single message express select and
+// nested plural forms that go-i18n's one/other pairs cannot. It returns
+// nil when the file has no such keys.
+//
+// A message that fails to compile is logged and skipped rather than
+// failing the whole translation load: the lookup for that id simply falls
+// through to the default content language's bundle, or to cfg.missing, the
+// same as any other untranslated id.
+func newICUBundle(logger loggers.Logger, lang string, data []byte) *icuBundle {
+	var raw map[string]interface{}
+	if err := toml.Unmarshal(data, &raw); err != nil {
+		// Not TOML (e.g. a YAML or JSON go-i18n file) - ICU markers are
+		// only recognized in TOML for now.
+		return nil
+	}
+
+	var messages map[string]*messageformat.Formatter
+	for key, v := range raw {
+		if !strings.HasSuffix(key, ".icu") {
+			continue
+		}
+		src, ok := v.(string)
+		if !ok {
+			continue
+		}
+		id := strings.TrimSuffix(key, ".icu")
+
+		parser, err := messageformat.NewParser(messageformat.WithLocale(lang))
+		if err != nil {
+			logger.Warnf("i18n: failed to create ICU parser for language %q, falling back to default content language: %s", lang, err)
+			continue
+		}
+		fm, err := parser.Parse(src)
+		if err != nil {
+			logger.Warnf("i18n: failed to compile ICU message %q for language %q, falling back to default content language: %s", id, lang, err)
+			continue
+		}
+
+		if messages == nil {
+			messages = make(map[string]*messageformat.Formatter)
+		}
+		messages[id] = fm
+	}
+
+	if messages == nil {
+		return nil
+	}
+
+	return &icuBundle{messages: messages}
+}
+
+// toMessageArgs maps a struct or map argument into the string-keyed
+// variables that the Fluent and ICU MessageFormat executors expect,
+// reusing the same reflection approach as getPluralCount so a
+// {{ .Count }}-style field also surfaces as the "count" variable used by
+// plural selectors.
+func toMessageArgs(args interface{}) map[string]interface{} {
+	vars := make(map[string]interface{})
+	if args == nil {
+		return vars
+	}
+
+	vars["count"] = getPluralCount(args)
+
+	v := reflect.ValueOf(args)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return vars
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			if key.Kind() != reflect.String {
+				continue
+			}
+			vars[key.String()] = v.MapIndex(key).Interface()
+		}
+	case reflect.Struct:
+		tp := v.Type()
+		for i := 0; i < tp.NumField(); i++ {
+			f := tp.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			vars[f.Name] = v.Field(i).Interface()
+		}
+	}
+
+	return vars
+}
+
+// getPluralCount resolves the plural count argument out of args, which may
+// be a bare number or string, a map with a "Count"/"count" key, or a
+// struct (or pointer to one) with a Count field or method.
+func getPluralCount(i interface{}) int {
+	if i == nil {
+		return 0
+	}
+
+	switch v := i.(type) {
+	case int:
+		return v
+	case int8:
+		return int(v)
+	case int16:
+		return int(v)
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case uint:
+		return int(v)
+	case uint8:
+		return int(v)
+	case uint16:
+		return int(v)
+	case uint32:
+		return int(v)
+	case uint64:
+		return int(v)
+	case float32:
+		return int(v)
+	case float64:
+		return int(v)
+	case string:
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return int(f)
+		}
+		return 0
+	}
+
+	vv := reflect.ValueOf(i)
+	for vv.Kind() == reflect.Ptr {
+		if vv.IsNil() {
+			return 0
+		}
+		vv = vv.Elem()
+	}
+
+	switch vv.Kind() {
+	case reflect.Map:
+		for _, key := range []string{"Count", "count"} {
+			kv := vv.MapIndex(reflect.ValueOf(key))
+			if kv.IsValid() {
+				return getPluralCount(kv.Interface())
+			}
+		}
+	case reflect.Struct:
+		if f := vv.FieldByName("Count"); f.IsValid() {
+			return getPluralCount(f.Interface())
+		}
+		if m := vv.MethodByName("Count"); m.IsValid() {
+			res := m.Call(nil)
+			if len(res) > 0 {
+				return getPluralCount(res[0].Interface())
+			}
+		}
+	}
+
+	return 0
+}
+
+// TranslationProvider provides translation handling, i.e. loading of
+// bundles etc.
+type TranslationProvider struct {
+	t Translator
+
+	// MissingTranslationFunc, when set, is consulted for every translation
+	// miss instead of the default enableMissingTranslationPlaceholders
+	// placeholder, e.g. to wire in machine-translation fallbacks, emit
+	// metrics, or write the missing id to a report for translator handoff.
+	// Set it via deps.DepsCfg before the provider's first Update.
+	MissingTranslationFunc MissingTranslationFunc
+}
+
+// NewTranslationProvider creates a new translation provider.
+func NewTranslationProvider() *TranslationProvider {
+	return &TranslationProvider{}
+}
+
+// Update updates the i18n translate func in the Deps.
+func (tp *TranslationProvider) Update(d *deps.Deps) error {
+	enablePlaceholders := d.Cfg.GetBool("enableMissingTranslationPlaceholders")
+
+	missing := tp.MissingTranslationFunc
+	if missing == nil {
+		missing = defaultMissingTranslationFunc(enablePlaceholders)
+	}
+
+	cfg := translatorConfig{
+		defaultContentLanguage: d.Cfg.GetString("defaultContentLanguage"),
+		enablePlaceholders:     enablePlaceholders,
+		missing:                missing,
+	}
+
+	t, err := NewTranslator(d.BaseFs.SourceFilesystems.I18n.Fs, cfg, d.Log)
+	if err != nil {
+		return err
+	}
+
+	tp.t = t
+	d.Translate = tp.t.Func(d.Language.Lang)
+
+	return nil
+}
+
+// Clone clones the translation provider into the given Deps.
+func (tp *TranslationProvider) Clone(d *deps.Deps) error {
+	return tp.Update(d)
+}
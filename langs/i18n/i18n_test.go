@@ -308,6 +308,120 @@ one =  "abc"`),
 		expected:     "abc",
 		expectedFlag: "abc",
 	},
+	// Fluent (.ftl) selectors can express gender/case/plural forms that
+	// go-i18n's TOML one/other pairs cannot.
+	{
+		name: "fluent-select",
+		data: map[string][]byte{
+			"en.ftl": []byte(`greeting = { $Gender ->
+    [male] He liked your post
+    [female] She liked your post
+   *[other] They liked your post
+}
+`),
+		},
+		args: struct {
+			Gender string
+		}{"female"},
+		lang:         "en",
+		id:           "greeting",
+		expected:     "She liked your post",
+		expectedFlag: "She liked your post",
+	},
+	// Fluent term references let translators factor out repeated phrases.
+	{
+		name: "fluent-term-reference",
+		data: map[string][]byte{
+			"en.ftl": []byte(`-brand-name = Hugo
+welcome = Welcome to { -brand-name }!
+`),
+		},
+		args:         nil,
+		lang:         "en",
+		id:           "welcome",
+		expected:     "Welcome to Hugo!",
+		expectedFlag: "Welcome to Hugo!",
+	},
+	// Translation missing in current language's FTL file but present in the
+	// default content language's FTL file.
+	{
+		name: "fluent-present-in-default",
+		data: map[string][]byte{
+			"en.ftl": []byte(`farewell = Goodbye, World!
+`),
+			"es.ftl": []byte(`hello = ¡Hola, Mundo!
+`),
+		},
+		args:         nil,
+		lang:         "es",
+		id:           "farewell",
+		expected:     "Goodbye, World!",
+		expectedFlag: "[i18n] farewell",
+	},
+	// ICU MessageFormat messages are marked with an ".icu" key suffix in the
+	// same TOML files, letting select and nested plural forms live in a
+	// single message.
+	{
+		name: "icu-select-and-plural",
+		data: map[string][]byte{
+			"en.toml": []byte(`"greeting.icu" = "{Gender, select, female {She} male {He} other {They}} liked {Count, plural, one {# post} other {# posts}}"`),
+		},
+		args: struct {
+			Gender string
+			Count  int
+		}{"female", 1},
+		lang:         "en",
+		id:           "greeting",
+		expected:     "She liked 1 post",
+		expectedFlag: "She liked 1 post",
+	},
+	{
+		name: "icu-nested-plural-many",
+		data: map[string][]byte{
+			"en.toml": []byte(`"greeting.icu" = "{Gender, select, female {She} male {He} other {They}} liked {Count, plural, one {# post} other {# posts}}"`),
+		},
+		args: struct {
+			Gender string
+			Count  int
+		}{"male", 3},
+		lang:         "en",
+		id:           "greeting",
+		expected:     "He liked 3 posts",
+		expectedFlag: "He liked 3 posts",
+	},
+	// Translation missing in current language's ICU message but present in
+	// the default content language.
+	{
+		name: "icu-present-in-default",
+		data: map[string][]byte{
+			"en.toml": []byte(`"count.icu" = "{Count, plural, one {# item} other {# items}}"`),
+			"es.toml": []byte("[hello]\nother = \"¡Hola, Mundo!\""),
+		},
+		args: struct {
+			Count int
+		}{5},
+		lang:         "es",
+		id:           "count",
+		expected:     "5 items",
+		expectedFlag: "[i18n] count",
+	},
+	// A malformed ICU pattern in the current language must not fail the
+	// whole load; it falls back to the default content language's
+	// compiled message like any other untranslated id.
+	{
+		name: "icu-parse-error-fallback-default",
+		data: map[string][]byte{
+			"en.toml": []byte(`"count.icu" = "{Count, plural, one {# item} other {# items}}"`),
+			"es.toml": []byte(`"count.icu" = "{Count, plural, one {# item} other"`),
+		},
+		args: struct {
+			Count int
+		}{2},
+		lang:         "es",
+		id:           "count",
+		expected:     "2 items",
+		expectedFlag: "[i18n] count",
+	},
 }
 
 func doTestI18nTranslate(t testing.TB, test i18nTest, cfg config.Provider) string {
@@ -357,6 +471,10 @@ func TestGetPluralCount(t *testing.T) {
 }
 
 func prepareTranslationProvider(t testing.TB, test i18nTest, cfg config.Provider) *TranslationProvider {
+	return prepareTranslationProviderWith(t, test, cfg, NewTranslationProvider())
+}
+
+func prepareTranslationProviderWith(t testing.TB, test i18nTest, cfg config.Provider, tp *TranslationProvider) *TranslationProvider {
 	c := qt.New(t)
 	fs := hugofs.NewMem(cfg)
 
@@ -365,7 +483,6 @@ func prepareTranslationProvider(t testing.TB, test i18nTest, cfg config.Provider
 		c.Assert(err, qt.IsNil)
 	}
 
-	tp := NewTranslationProvider()
 	depsCfg := newDepsConfig(tp, cfg, fs)
 	d, err := deps.New(depsCfg)
 	c.Assert(err, qt.IsNil)
@@ -432,6 +549,53 @@ func TestI18nTranslate(t *testing.T) {
 	}
 }
 
+// recordedMiss captures the arguments a MissingTranslationFunc was called
+// with, for assertion.
+type recordedMiss struct {
+	lang, id string
+	args     interface{}
+}
+
+func TestI18nTranslateMissingTranslationFunc(t *testing.T) {
+	c := qt.New(t)
+	v := getConfig()
+	// Force every one of the cases below to be a genuine miss, including
+	// present-in-default, which would otherwise be resolved via the
+	// default-language fallback.
+	v.Set("enableMissingTranslationPlaceholders", true)
+
+	missCases := map[string]i18nTest{}
+	for _, test := range i18nTests {
+		switch test.name {
+		case "missing", "file-missing", "present-in-default":
+			missCases[test.name] = test
+		}
+	}
+	c.Assert(missCases, qt.HasLen, 3)
+
+	for name, test := range missCases {
+		c.Run(name, func(c *qt.C) {
+			var recorded []recordedMiss
+
+			tp := NewTranslationProvider()
+			tp.MissingTranslationFunc = func(lang, id string, args interface{}) (string, bool) {
+				recorded = append(recorded, recordedMiss{lang, id, args})
+				return "** MISSING **", true
+			}
+
+			tp = prepareTranslationProviderWith(c, test, v, tp)
+			f := tp.t.Func(test.lang)
+			actual := f(test.id, test.args)
+
+			c.Assert(actual, qt.Equals, "** MISSING **")
+			c.Assert(recorded, qt.HasLen, 1)
+			c.Assert(recorded[0].lang, qt.Equals, test.lang)
+			c.Assert(recorded[0].id, qt.Equals, test.id)
+			c.Assert(recorded[0].args, qt.DeepEquals, test.args)
+		})
+	}
+}
+
 func BenchmarkI18nTranslate(b *testing.B) {
 	v := getConfig()
 	for _, test := range i18nTests {